@@ -5,8 +5,12 @@ package certutil
 
 import (
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
 	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
 )
 
 // Source of the issuance request: sign implies that the key material was
@@ -72,6 +76,14 @@ type CIEPSVaultParams struct {
 	IssuerCert string `json:"requested_issuer_cert"`
 
 	Config CIEPSIssuanceConfig `json:"requested_issuance_config"`
+
+	// PreferredChainIssuerCN, when set from a role or mount option, asks
+	// the external CIEPS service (when it returns multiple candidate
+	// chains via CIEPSResponse.Chains) to let Vault select the chain
+	// whose topmost issuer's CN matches this value, analogous to ACME's
+	// "preferred chain" behavior. If no chain matches, Vault falls back
+	// to the first entry in Chains.
+	PreferredChainIssuerCN string `json:"preferred_chain_issuer_cn,omitempty"`
 }
 
 // Outer request object sent by Vault to the external CIEPS service.
@@ -141,8 +153,159 @@ type CIEPSResponse struct {
 	IssuerRef         string            `json:"issuer_ref"`
 	StoreCert         bool              `json:"store_certificate"`
 	GenerateLease     bool              `json:"generate_lease"`
+
+	// Chains, when set, lists multiple candidate certificate chains for
+	// the issued certificate, analogous to ACME's "preferred chain"
+	// behavior. SelectChain picks the entry whose topmost issuer CN
+	// matches CIEPSVaultParams.PreferredChainIssuerCN, falling back to
+	// Chains[0] otherwise. SelectChain returns the selected leaf; it does
+	// not update Certificate/ParsedCertificate above, which are set and
+	// marshaled independently of Chains.
+	Chains []CIEPSCertChain `json:"chains,omitempty"`
+
+	// OCSPResponse, when set, is a base64-encoded DER OCSP response
+	// produced by the external CIEPS service for the issued certificate.
+	// When present, Vault's OCSP responder serves it directly rather than
+	// constructing and signing its own response for this serial number.
+	OCSPResponse string `json:"ocsp_response,omitempty"`
+
+	// ParsedOCSPResponse is the parsed form of OCSPResponse, populated by
+	// ParseOCSPResponse.
+	ParsedOCSPResponse *ocsp.Response `json:"-"`
+
+	// OCSPNextUpdate is an RFC3339 timestamp indicating when Vault should
+	// stop serving OCSPResponse and instead fall back to its own
+	// revocation logic (or re-request from CIEPS, once the async flow
+	// supports refresh). Only meaningful alongside OCSPResponse.
+	OCSPNextUpdate string `json:"ocsp_next_update,omitempty"`
+
+	// RevocationHooks, when set, describes a webhook Vault should call on
+	// pki/revoke instead of (or in addition to) performing local
+	// revocation bookkeeping, for CAs where the external CIEPS service is
+	// the authoritative source of revocation status.
+	RevocationHooks *CIEPSRevocationHooks `json:"revocation_hooks,omitempty"`
+
+	// Signature is the compact-serialized JWS produced by the external
+	// CIEPS service over this response, signing the wire format
+	// independent of transport TLS. Populated by ParseSignedResponse;
+	// use VerifyResponse to check it against the mount's configured
+	// JWKS before trusting any other field on this struct.
+	Signature string `json:"-"`
+}
+
+// CIEPSRevocationHooks describes where and how Vault should notify an
+// external CIEPS-backed CA of a pki/revoke call, and the distribution
+// point clients should be pointed at for CRL-based revocation checking.
+type CIEPSRevocationHooks struct {
+	// WebhookURL is called by Vault on pki/revoke with the serial number
+	// and issuer_ref of the revoked certificate.
+	WebhookURL string `json:"webhook_url"`
+
+	// CRLDistributionPoint is the CRL URL the external CA publishes for
+	// the issued certificate; Vault may include it in the issued
+	// certificate's CRL distribution points extension if not already
+	// templated on the issuer.
+	CRLDistributionPoint string `json:"crl_distribution_point,omitempty"`
+
+	// RevocationReasonHint suggests the CRLReason (RFC 5280 §5.3.1) Vault
+	// should record and, where applicable, forward to the webhook when
+	// revoking this certificate.
+	RevocationReasonHint int `json:"revocation_reason_hint,omitempty"`
+}
+
+// ParseOCSPResponse decodes and validates c.OCSPResponse, cross-checking
+// the embedded OCSP response against ParsedCertificate.SerialNumber and
+// against issuerRef, the reference Vault resolved issuer to. issuer must
+// be the certificate named by c.IssuerRef, used by ocsp.ParseResponse to
+// verify the OCSP response's signature; a nil issuer would silently skip
+// that signature check and let Vault serve an unauthenticated,
+// attacker-supplied OCSP response, so it is required. ParseOCSPResponse is
+// a no-op if OCSPResponse is unset.
+func (c *CIEPSResponse) ParseOCSPResponse(issuer *x509.Certificate, issuerRef string) error {
+	if c.OCSPResponse == "" {
+		return nil
+	}
+
+	if issuer == nil {
+		return fmt.Errorf("an issuer certificate is required to validate 'ocsp_response'")
+	}
+
+	if issuerRef != c.IssuerRef {
+		return fmt.Errorf("issuer reference %q used to resolve the OCSP signer does not match response's issuer_ref %q", issuerRef, c.IssuerRef)
+	}
+
+	der, err := base64.StdEncoding.DecodeString(c.OCSPResponse)
+	if err != nil {
+		return fmt.Errorf("failed to base64-decode 'ocsp_response': %w", err)
+	}
+
+	resp, err := ocsp.ParseResponse(der, issuer)
+	if err != nil {
+		return fmt.Errorf("failed to parse 'ocsp_response': %w", err)
+	}
+
+	if c.ParsedCertificate != nil && resp.SerialNumber != nil &&
+		c.ParsedCertificate.SerialNumber.Cmp(resp.SerialNumber) != 0 {
+		return fmt.Errorf("'ocsp_response' serial number %s does not match issued certificate serial number %s",
+			resp.SerialNumber, c.ParsedCertificate.SerialNumber)
+	}
+
+	if c.OCSPNextUpdate != "" {
+		nextUpdate, err := time.Parse(time.RFC3339, c.OCSPNextUpdate)
+		if err != nil {
+			return fmt.Errorf("failed to parse 'ocsp_next_update' as RFC3339: %w", err)
+		}
+		if !resp.NextUpdate.IsZero() && !nextUpdate.Equal(resp.NextUpdate) {
+			return fmt.Errorf("'ocsp_next_update' (%s) does not match embedded OCSP response NextUpdate (%s)",
+				nextUpdate, resp.NextUpdate)
+		}
+	}
+
+	c.ParsedOCSPResponse = resp
+	return nil
+}
+
+// MarshalOCSPResponse is the symmetric counterpart of ParseOCSPResponse,
+// used by a CIEPS service implementation (or tests) to populate
+// OCSPResponse from a raw DER-encoded OCSP response.
+func (c *CIEPSResponse) MarshalOCSPResponse(der []byte) error {
+	if len(der) == 0 {
+		return fmt.Errorf("no OCSP response present")
+	}
+
+	c.OCSPResponse = base64.StdEncoding.EncodeToString(der)
+	return nil
+}
+
+// CIEPSCertChain is a single candidate certificate chain returned by the
+// external CIEPS service. IssuerCN is an informational label reported by
+// the service; it is never trusted for chain selection, which instead
+// derives the topmost issuer's CN from the parsed certificates
+// themselves (see SelectChain).
+type CIEPSCertChain struct {
+	// Certificate is the PEM-encoded leaf certificate for this chain.
+	Certificate string `json:"certificate"`
+
+	// CAChain is the ordered list of PEM-encoded intermediate and root
+	// certificates for this chain, excluding the leaf.
+	CAChain []string `json:"ca_chain"`
+
+	// IssuerCN is the CN of the topmost (root or cross-signing) issuer in
+	// CAChain, as reported by the CIEPS service.
+	IssuerCN string `json:"issuer_cn"`
+
+	// ParsedCertificate and ParsedCAChain are the parsed leaf and CA
+	// certificates for this chain. MarshalCertificate derives
+	// Certificate, CAChain, and IssuerCN from them, mirroring how
+	// ParsedCertificate/Certificate work on CIEPSResponse itself.
+	ParsedCertificate *x509.Certificate   `json:"-"`
+	ParsedCAChain     []*x509.Certificate `json:"-"`
 }
 
+// MarshalCertificate marshals c.ParsedCertificate into c.Certificate, and
+// does the same for each entry of c.Chains whose ParsedCertificate is
+// set, also deriving that entry's CAChain and IssuerCN from its
+// ParsedCAChain.
 func (c *CIEPSResponse) MarshalCertificate() error {
 	if c.ParsedCertificate == nil || len(c.ParsedCertificate.Raw) == 0 {
 		return fmt.Errorf("no certificate present")
@@ -157,5 +320,127 @@ func (c *CIEPSResponse) MarshalCertificate() error {
 	}
 	c.Certificate = string(pem)
 
+	for i := range c.Chains {
+		if c.Chains[i].ParsedCertificate == nil {
+			continue
+		}
+
+		if err := c.Chains[i].marshal(); err != nil {
+			return fmt.Errorf("failed to marshal chain %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// marshal derives Certificate, CAChain, and IssuerCN from
+// ParsedCertificate/ParsedCAChain.
+func (chain *CIEPSCertChain) marshal() error {
+	if len(chain.ParsedCertificate.Raw) == 0 {
+		return fmt.Errorf("no certificate present")
+	}
+
+	leafPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: chain.ParsedCertificate.Raw,
+	})
+	if len(leafPEM) == 0 {
+		return fmt.Errorf("failed to generate PEM: no body")
+	}
+	chain.Certificate = string(leafPEM)
+
+	caChain := make([]string, 0, len(chain.ParsedCAChain))
+	for i, ca := range chain.ParsedCAChain {
+		if ca == nil || len(ca.Raw) == 0 {
+			return fmt.Errorf("no certificate present for CA %d", i)
+		}
+
+		caPEM := pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: ca.Raw,
+		})
+		if len(caPEM) == 0 {
+			return fmt.Errorf("failed to generate PEM for CA %d: no body", i)
+		}
+		caChain = append(caChain, string(caPEM))
+	}
+	chain.CAChain = caChain
+
+	if len(chain.ParsedCAChain) > 0 {
+		chain.IssuerCN = chain.ParsedCAChain[len(chain.ParsedCAChain)-1].Subject.CommonName
+	}
+
 	return nil
 }
+
+// SelectChain picks the entry of c.Chains whose topmost issuer's CN,
+// derived from parsing CAChain (falling back to the leaf's own Issuer CN
+// when CAChain is empty), matches preferredCN. It falls back to
+// Chains[0] if no entry matches or preferredCN is empty. The match is
+// always made against the parsed certificates, never against the
+// service-reported IssuerCN label, so a mislabeled or hostile response
+// cannot pin the wrong chain. If c.Chains is empty, it falls back to
+// c.ParsedCertificate (with no CA chain), preserving single-chain
+// responses that predate this field.
+func (c *CIEPSResponse) SelectChain(preferredCN string) (*x509.Certificate, []*x509.Certificate, error) {
+	if len(c.Chains) == 0 {
+		if c.ParsedCertificate == nil {
+			return nil, nil, fmt.Errorf("no certificate chains present")
+		}
+		return c.ParsedCertificate, nil, nil
+	}
+
+	type parsedChain struct {
+		leaf      *x509.Certificate
+		caChain   []*x509.Certificate
+		topmostCN string
+	}
+
+	parsed := make([]parsedChain, 0, len(c.Chains))
+	for i, chain := range c.Chains {
+		leaf, err := parseSinglePEMCertificate(chain.Certificate)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse chain %d's certificate: %w", i, err)
+		}
+
+		caChain := make([]*x509.Certificate, 0, len(chain.CAChain))
+		for j, caPEM := range chain.CAChain {
+			ca, err := parseSinglePEMCertificate(caPEM)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to parse chain %d's CA certificate %d: %w", i, j, err)
+			}
+			caChain = append(caChain, ca)
+		}
+
+		topmostCN := leaf.Issuer.CommonName
+		if len(caChain) > 0 {
+			topmostCN = caChain[len(caChain)-1].Subject.CommonName
+		}
+
+		parsed = append(parsed, parsedChain{leaf: leaf, caChain: caChain, topmostCN: topmostCN})
+	}
+
+	selected := parsed[0]
+	if preferredCN != "" {
+		for _, candidate := range parsed {
+			if candidate.topmostCN == preferredCN {
+				selected = candidate
+				break
+			}
+		}
+	}
+
+	return selected.leaf, selected.caChain, nil
+}
+
+func parseSinglePEMCertificate(certPEM string) (*x509.Certificate, error) {
+	block, rest := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%v bytes of trailing data after PEM block", len(rest))
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
@@ -0,0 +1,135 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package certutil
+
+import (
+	"fmt"
+	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// CIEPSPendingResponse is returned by an external CIEPS service in place
+// of a CIEPSResponse when a request was submitted with Sync=false and
+// cannot be completed immediately (e.g. CMPv2 or manual-approval backed
+// CAs). Vault persists it, keyed by the originating request's UUID, in a
+// storage-backed queue (see PendingCIEPSRequestStore) and exposes its
+// status at pki/cieps/pending/{uuid}.
+type CIEPSPendingResponse struct {
+	UUID string `json:"request_uuid"`
+
+	// RetryAfter is the minimum number of seconds Vault's background
+	// poller should wait before re-polling the external service for this
+	// request.
+	RetryAfter int `json:"retry_after"`
+
+	// PollToken is opaque to Vault and passed back to the external
+	// service on each poll so it can look up in-progress work.
+	PollToken string `json:"poll_token"`
+
+	// CallbackNonce must be echoed by the external service's eventual
+	// callback to pki/cieps/callback; it binds the callback to this
+	// specific pending request independent of the JWS signature checked
+	// by CIEPSResponse.VerifyResponse.
+	CallbackNonce string `json:"callback_nonce"`
+}
+
+// CIEPSServiceResponse is the raw envelope an external CIEPS service
+// returns for an async-capable request: exactly one of Response or
+// Pending is populated.
+type CIEPSServiceResponse struct {
+	Response *CIEPSResponse        `json:"response,omitempty"`
+	Pending  *CIEPSPendingResponse `json:"pending,omitempty"`
+}
+
+// Validate checks that exactly one of Response or Pending is set.
+func (r *CIEPSServiceResponse) Validate() error {
+	if (r.Response == nil) == (r.Pending == nil) {
+		return fmt.Errorf("exactly one of 'response' or 'pending' must be set on a CIEPS service response")
+	}
+	return nil
+}
+
+// CIEPSPendingRequest is the record the PKI backend's storage-backed
+// queue keeps for a request awaiting asynchronous completion. It is the
+// unit of work a PendingCIEPSRequestStore persists and the background
+// re-poller operates on.
+type CIEPSPendingRequest struct {
+	Request CIEPSRequest
+	Pending CIEPSPendingResponse
+
+	// SubmittedAt is when the request was first sent to the external
+	// service; combined with the mount's MountMaxTTL, it bounds how long
+	// Vault will keep re-polling before treating the request as
+	// abandoned and cleaning it up.
+	SubmittedAt time.Time
+
+	// NextPollAt is when the background worker should next re-poll the
+	// external service, derived from Pending.RetryAfter.
+	NextPollAt time.Time
+}
+
+// Expired reports whether this pending request has outlived the mount's
+// configured max TTL and should be abandoned and cleaned up by the
+// background worker without further polling.
+func (p *CIEPSPendingRequest) Expired(mountMaxTTL time.Duration) bool {
+	return mountMaxTTL > 0 && time.Since(p.SubmittedAt) > mountMaxTTL
+}
+
+// DuePoll reports whether the background worker should re-poll the
+// external service for this request now.
+func (p *CIEPSPendingRequest) DuePoll() bool {
+	return !p.NextPollAt.IsZero() && !time.Now().Before(p.NextPollAt)
+}
+
+// ScheduleNextPoll sets NextPollAt to retryAfterSeconds (from the most
+// recent CIEPSPendingResponse) from now, enforcing a minimum of one second so
+// a misbehaving external service cannot cause a tight re-poll loop.
+func (p *CIEPSPendingRequest) ScheduleNextPoll(retryAfterSeconds int) {
+	if retryAfterSeconds <= 0 {
+		retryAfterSeconds = 1
+	}
+	p.NextPollAt = time.Now().Add(time.Duration(retryAfterSeconds) * time.Second)
+}
+
+// VerifyCallback validates an inbound pki/cieps/callback request for this
+// pending request: the callback's nonce must match the nonce Vault
+// handed out in Pending.CallbackNonce, its JWS must verify against jwks,
+// and its request_uuid must match the originating request. On success it
+// returns the verified CIEPSResponse to be processed exactly as a
+// synchronous response would be, and clears Pending.CallbackNonce so the
+// same callback is not accepted twice for this record.
+func (p *CIEPSPendingRequest) VerifyCallback(token []byte, nonce string, jwks jose.JSONWebKeySet) (*CIEPSResponse, error) {
+	if nonce == "" || nonce != p.Pending.CallbackNonce {
+		return nil, fmt.Errorf("CIEPS callback nonce does not match pending request %q", p.Request.UUID)
+	}
+
+	resp, err := ParseSignedResponse(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CIEPS callback: %w", err)
+	}
+
+	// The one-time CallbackNonce checked above already binds this
+	// callback to this specific pending request, so no additional
+	// JWS-nonce replay check is needed here.
+	if err := resp.VerifyResponse(jwks, p.Request.UUID, nil); err != nil {
+		return nil, fmt.Errorf("failed to verify CIEPS callback: %w", err)
+	}
+
+	p.Pending.CallbackNonce = ""
+	return resp, nil
+}
+
+// PendingCIEPSRequestStore is the storage-backed queue of in-flight
+// asynchronous CIEPS requests. certutil defines only this contract; the
+// PKI backend implements it against Vault's logical.Storage, keyed by
+// request UUID, and drives it from a background worker that re-polls
+// the external service on each entry's schedule, honors MountMaxTTL, and
+// removes abandoned entries.
+type PendingCIEPSRequestStore interface {
+	Put(pending *CIEPSPendingRequest) error
+	Get(uuid string) (*CIEPSPendingRequest, bool, error)
+	Delete(uuid string) error
+	List() ([]string, error)
+}
@@ -0,0 +1,134 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package certutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCIEPSServiceResponse_Validate(t *testing.T) {
+	cases := []struct {
+		name    string
+		r       CIEPSServiceResponse
+		wantErr bool
+	}{
+		{"only response set", CIEPSServiceResponse{Response: &CIEPSResponse{}}, false},
+		{"only pending set", CIEPSServiceResponse{Pending: &CIEPSPendingResponse{}}, false},
+		{"neither set", CIEPSServiceResponse{}, true},
+		{"both set", CIEPSServiceResponse{Response: &CIEPSResponse{}, Pending: &CIEPSPendingResponse{}}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.r.Validate()
+			if tc.wantErr && err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestCIEPSPendingRequest_Expired(t *testing.T) {
+	p := &CIEPSPendingRequest{SubmittedAt: time.Now().Add(-2 * time.Hour)}
+
+	if p.Expired(0) {
+		t.Fatalf("expected no expiry when mountMaxTTL is 0 (unbounded)")
+	}
+	if !p.Expired(time.Hour) {
+		t.Fatalf("expected the request to be expired after exceeding mountMaxTTL")
+	}
+	if p.Expired(3 * time.Hour) {
+		t.Fatalf("expected the request not to be expired within mountMaxTTL")
+	}
+}
+
+func TestCIEPSPendingRequest_DuePoll(t *testing.T) {
+	p := &CIEPSPendingRequest{}
+	if p.DuePoll() {
+		t.Fatalf("expected no poll due with a zero NextPollAt")
+	}
+
+	p.NextPollAt = time.Now().Add(-time.Minute)
+	if !p.DuePoll() {
+		t.Fatalf("expected a poll to be due once NextPollAt has passed")
+	}
+
+	p.NextPollAt = time.Now().Add(time.Hour)
+	if p.DuePoll() {
+		t.Fatalf("expected no poll due before NextPollAt")
+	}
+}
+
+func TestCIEPSPendingRequest_ScheduleNextPoll(t *testing.T) {
+	p := &CIEPSPendingRequest{}
+
+	p.ScheduleNextPoll(30)
+	if p.NextPollAt.Before(time.Now().Add(29*time.Second)) || p.NextPollAt.After(time.Now().Add(31*time.Second)) {
+		t.Fatalf("expected NextPollAt to be roughly 30s out, got %v", p.NextPollAt)
+	}
+
+	p.ScheduleNextPoll(0)
+	if p.NextPollAt.Before(time.Now()) || p.NextPollAt.After(time.Now().Add(2*time.Second)) {
+		t.Fatalf("expected a non-positive retry-after to fall back to a minimum of 1s, got %v", p.NextPollAt)
+	}
+}
+
+func TestCIEPSPendingRequest_VerifyCallback(t *testing.T) {
+	signer := testGenerateJWSSigner(t)
+	otherSigner := testGenerateJWSSigner(t)
+
+	newPending := func() *CIEPSPendingRequest {
+		return &CIEPSPendingRequest{
+			Request: CIEPSRequest{UUID: "req-1"},
+			Pending: CIEPSPendingResponse{UUID: "req-1", CallbackNonce: "cb-nonce"},
+		}
+	}
+
+	sign := func(uuid string) []byte {
+		return testSignCIEPSResponse(t, CIEPSResponse{UUID: uuid}, time.Now().Unix(), "jws-nonce", signer, "kid-1")
+	}
+
+	t.Run("valid callback", func(t *testing.T) {
+		p := newPending()
+		resp, err := p.VerifyCallback(sign("req-1"), "cb-nonce", testJWKS(signer, "kid-1"))
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if resp.UUID != "req-1" {
+			t.Fatalf("expected the verified response to be returned")
+		}
+	})
+
+	t.Run("wrong callback nonce is rejected", func(t *testing.T) {
+		p := newPending()
+		if _, err := p.VerifyCallback(sign("req-1"), "wrong-nonce", testJWKS(signer, "kid-1")); err == nil {
+			t.Fatalf("expected an error for a mismatched callback nonce")
+		}
+	})
+
+	t.Run("empty callback nonce is rejected", func(t *testing.T) {
+		p := newPending()
+		if _, err := p.VerifyCallback(sign("req-1"), "", testJWKS(signer, "kid-1")); err == nil {
+			t.Fatalf("expected an error for an empty callback nonce")
+		}
+	})
+
+	t.Run("untrusted signer is rejected", func(t *testing.T) {
+		p := newPending()
+		if _, err := p.VerifyCallback(sign("req-1"), "cb-nonce", testJWKS(otherSigner, "kid-1")); err == nil {
+			t.Fatalf("expected an error when the callback is signed by an untrusted key")
+		}
+	})
+
+	t.Run("mismatched request_uuid is rejected", func(t *testing.T) {
+		p := newPending()
+		if _, err := p.VerifyCallback(sign("some-other-request"), "cb-nonce", testJWKS(signer, "kid-1")); err == nil {
+			t.Fatalf("expected an error when the callback's request_uuid does not match the pending request")
+		}
+	})
+}
@@ -0,0 +1,163 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package certutil
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func testPEMEncodeCert(cert *x509.Certificate) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
+
+func TestCIEPSResponse_MarshalCertificate_WithChains(t *testing.T) {
+	ca, caKey := testGenerateTestCA(t, "Test Root CA")
+	leaf := testGenerateTestLeaf(t, ca, caKey, 1, "leaf.example.com")
+	chainLeaf := testGenerateTestLeaf(t, ca, caKey, 2, "chain-leaf.example.com")
+
+	c := &CIEPSResponse{
+		ParsedCertificate: leaf,
+		Chains: []CIEPSCertChain{
+			{
+				ParsedCertificate: chainLeaf,
+				ParsedCAChain:     []*x509.Certificate{ca},
+			},
+		},
+	}
+
+	if err := c.MarshalCertificate(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if c.Certificate == "" {
+		t.Fatalf("expected top-level Certificate to be populated")
+	}
+	if c.Chains[0].Certificate == "" {
+		t.Fatalf("expected chain Certificate to be populated")
+	}
+	if len(c.Chains[0].CAChain) != 1 || c.Chains[0].CAChain[0] != testPEMEncodeCert(ca) {
+		t.Fatalf("expected chain CAChain to be populated from ParsedCAChain")
+	}
+	if c.Chains[0].IssuerCN != ca.Subject.CommonName {
+		t.Fatalf("expected chain IssuerCN to be derived from the topmost parsed CA, got %q", c.Chains[0].IssuerCN)
+	}
+}
+
+func TestCIEPSResponse_MarshalCertificate_NoCertificate(t *testing.T) {
+	c := &CIEPSResponse{}
+	if err := c.MarshalCertificate(); err == nil {
+		t.Fatalf("expected an error when ParsedCertificate is unset")
+	}
+}
+
+func TestCIEPSResponse_MarshalCertificate_SkipsUnparsedChains(t *testing.T) {
+	ca, caKey := testGenerateTestCA(t, "Test Root CA")
+	leaf := testGenerateTestLeaf(t, ca, caKey, 1, "leaf.example.com")
+
+	c := &CIEPSResponse{
+		ParsedCertificate: leaf,
+		Chains: []CIEPSCertChain{
+			{Certificate: "already-set", IssuerCN: "some-label"},
+		},
+	}
+
+	if err := c.MarshalCertificate(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if c.Chains[0].Certificate != "already-set" {
+		t.Fatalf("expected a chain entry with no ParsedCertificate to be left untouched")
+	}
+}
+
+func TestCIEPSResponse_SelectChain(t *testing.T) {
+	rootA, rootAKey := testGenerateTestCA(t, "Root A")
+	rootB, rootBKey := testGenerateTestCA(t, "Root B")
+	leafA := testGenerateTestLeaf(t, rootA, rootAKey, 10, "leaf-a.example.com")
+	leafB := testGenerateTestLeaf(t, rootB, rootBKey, 11, "leaf-b.example.com")
+
+	chains := []CIEPSCertChain{
+		{
+			Certificate: testPEMEncodeCert(leafA),
+			CAChain:     []string{testPEMEncodeCert(rootA)},
+			// Deliberately mislabeled: the service claims this chain is
+			// rooted at rootB's CN, but the embedded CAChain is actually
+			// rooted at rootA.
+			IssuerCN: rootB.Subject.CommonName,
+		},
+		{
+			Certificate: testPEMEncodeCert(leafB),
+			CAChain:     []string{testPEMEncodeCert(rootB)},
+			IssuerCN:    rootB.Subject.CommonName,
+		},
+	}
+
+	t.Run("selects by actual parsed CN, ignoring the untrusted label", func(t *testing.T) {
+		c := &CIEPSResponse{Chains: chains}
+		leaf, caChain, err := c.SelectChain(rootA.Subject.CommonName)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if leaf.SerialNumber.Cmp(leafA.SerialNumber) != 0 {
+			t.Fatalf("expected to select the chain actually rooted at %q", rootA.Subject.CommonName)
+		}
+		if len(caChain) != 1 || !caChain[0].Equal(rootA) {
+			t.Fatalf("expected the selected chain's parsed CA chain to be rootA")
+		}
+	})
+
+	t.Run("does not select on the mislabeled claim alone", func(t *testing.T) {
+		c := &CIEPSResponse{Chains: chains}
+		// rootB's CN is claimed (falsely) by chains[0] and truly by
+		// chains[1]; since selection walks in order and chains[1] is the
+		// only entry whose *actual* topmost CN matches, it must win.
+		leaf, _, err := c.SelectChain(rootB.Subject.CommonName)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if leaf.SerialNumber.Cmp(leafB.SerialNumber) != 0 {
+			t.Fatalf("expected to select the chain actually rooted at %q, not the mislabeled one", rootB.Subject.CommonName)
+		}
+	})
+
+	t.Run("falls back to the first chain when nothing matches", func(t *testing.T) {
+		c := &CIEPSResponse{Chains: chains}
+		leaf, _, err := c.SelectChain("no-such-cn")
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if leaf.SerialNumber.Cmp(leafA.SerialNumber) != 0 {
+			t.Fatalf("expected fallback to the first chain entry")
+		}
+	})
+
+	t.Run("falls back to ParsedCertificate when there are no chains", func(t *testing.T) {
+		c := &CIEPSResponse{ParsedCertificate: leafA}
+		leaf, caChain, err := c.SelectChain("anything")
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if leaf != leafA {
+			t.Fatalf("expected the single parsed certificate to be returned")
+		}
+		if caChain != nil {
+			t.Fatalf("expected a nil CA chain in the single-certificate fallback")
+		}
+	})
+
+	t.Run("errors with no chains and no parsed certificate", func(t *testing.T) {
+		c := &CIEPSResponse{}
+		if _, _, err := c.SelectChain(""); err == nil {
+			t.Fatalf("expected an error when no certificate is present at all")
+		}
+	})
+
+	t.Run("errors on malformed chain PEM", func(t *testing.T) {
+		c := &CIEPSResponse{Chains: []CIEPSCertChain{{Certificate: "not pem"}}}
+		if _, _, err := c.SelectChain(""); err == nil {
+			t.Fatalf("expected an error on malformed certificate PEM")
+		}
+	})
+}
@@ -0,0 +1,83 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package certutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+)
+
+// CIEPSGRPCTransportConfig holds the mTLS and connection settings for the
+// gRPC transport to a CIEPS backend, configured per PKI mount alongside the
+// existing HTTP/JSON address. The generated client/server stubs for the
+// CIEPSService defined in cieps.proto are expected to live alongside the
+// rest of the protoc-generated code for this module and are not duplicated
+// here; this file covers the hand-written transport wiring Vault needs on
+// top of them.
+type CIEPSGRPCTransportConfig struct {
+	// Address is the CIEPS gRPC endpoint, e.g. "cieps.example.com:8443".
+	Address string `json:"address"`
+
+	// ClientCertFile/ClientKeyFile identify Vault to the CIEPS backend.
+	ClientCertFile string `json:"client_cert_file"`
+	ClientKeyFile  string `json:"client_key_file"`
+
+	// CABundleFile validates the CIEPS backend's server certificate.
+	CABundleFile string `json:"ca_bundle_file"`
+
+	// DefaultTimeout bounds a synchronous request when the caller does not
+	// otherwise supply a context deadline.
+	DefaultTimeout time.Duration `json:"default_timeout"`
+}
+
+// TLSConfig builds the client-side mTLS configuration from the configured
+// certificate, key, and CA bundle files.
+func (c *CIEPSGRPCTransportConfig) TLSConfig() (*tls.Config, error) {
+	if c.ClientCertFile == "" || c.ClientKeyFile == "" {
+		return nil, fmt.Errorf("missing client certificate or key for CIEPS gRPC transport")
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.ClientCertFile, c.ClientKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CIEPS client certificate: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if c.CABundleFile != "" {
+		caBytes, err := os.ReadFile(c.CABundleFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CIEPS CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(caBytes) {
+			return nil, fmt.Errorf("failed to parse CIEPS CA bundle: no certificates found")
+		}
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// RequestTimeout derives the per-request deadline to apply to a gRPC call
+// for req. Synchronous requests use the configured DefaultTimeout (falling
+// back to a conservative default) so a single long-lived stream connection
+// cannot block indefinitely on one slow issuance; asynchronous requests
+// have no deadline here, since completion is tracked out-of-band via the
+// pending-request poll/callback flow.
+func (c *CIEPSGRPCTransportConfig) RequestTimeout(req *CIEPSRequest) (time.Duration, bool) {
+	if req == nil || !req.Sync {
+		return 0, false
+	}
+
+	if c.DefaultTimeout > 0 {
+		return c.DefaultTimeout, true
+	}
+
+	return 30 * time.Second, true
+}
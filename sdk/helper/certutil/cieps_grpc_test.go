@@ -0,0 +1,144 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package certutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testGenerateSelfSignedCert generates a throwaway self-signed ECDSA
+// certificate and key for use across this file's tests.
+func testGenerateSelfSignedCert(t *testing.T, cn string) (certPEM []byte, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate test key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:         true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create test certificate: %v", err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal test key: %v", err)
+	}
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+
+	return certPEM, keyPEM
+}
+
+func testWriteTempFile(t *testing.T, dir, name string, contents []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, contents, 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestCIEPSGRPCTransportConfig_TLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certPEM, keyPEM := testGenerateSelfSignedCert(t, "cieps-client")
+
+	certFile := testWriteTempFile(t, dir, "client.crt", certPEM)
+	keyFile := testWriteTempFile(t, dir, "client.key", keyPEM)
+	caFile := testWriteTempFile(t, dir, "ca.crt", certPEM)
+
+	t.Run("valid config", func(t *testing.T) {
+		cfg := &CIEPSGRPCTransportConfig{
+			ClientCertFile: certFile,
+			ClientKeyFile:  keyFile,
+			CABundleFile:   caFile,
+		}
+
+		tlsConfig, err := cfg.TLSConfig()
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if len(tlsConfig.Certificates) != 1 {
+			t.Fatalf("expected 1 client certificate, got %d", len(tlsConfig.Certificates))
+		}
+		if tlsConfig.RootCAs == nil {
+			t.Fatalf("expected a non-nil CA pool")
+		}
+	})
+
+	t.Run("missing client cert", func(t *testing.T) {
+		cfg := &CIEPSGRPCTransportConfig{CABundleFile: caFile}
+		if _, err := cfg.TLSConfig(); err == nil {
+			t.Fatalf("expected an error for missing client certificate")
+		}
+	})
+
+	t.Run("unreadable CA bundle", func(t *testing.T) {
+		cfg := &CIEPSGRPCTransportConfig{
+			ClientCertFile: certFile,
+			ClientKeyFile:  keyFile,
+			CABundleFile:   filepath.Join(dir, "does-not-exist.crt"),
+		}
+		if _, err := cfg.TLSConfig(); err == nil {
+			t.Fatalf("expected an error for a missing CA bundle file")
+		}
+	})
+
+	t.Run("malformed CA bundle", func(t *testing.T) {
+		badCAFile := testWriteTempFile(t, dir, "bad-ca.crt", []byte("not a certificate"))
+		cfg := &CIEPSGRPCTransportConfig{
+			ClientCertFile: certFile,
+			ClientKeyFile:  keyFile,
+			CABundleFile:   badCAFile,
+		}
+		if _, err := cfg.TLSConfig(); err == nil {
+			t.Fatalf("expected an error for a malformed CA bundle")
+		}
+	})
+}
+
+func TestCIEPSGRPCTransportConfig_RequestTimeout(t *testing.T) {
+	cfg := &CIEPSGRPCTransportConfig{}
+
+	if _, ok := cfg.RequestTimeout(nil); ok {
+		t.Fatalf("expected no timeout for a nil request")
+	}
+
+	if _, ok := cfg.RequestTimeout(&CIEPSRequest{Sync: false}); ok {
+		t.Fatalf("expected no timeout for an asynchronous request")
+	}
+
+	dur, ok := cfg.RequestTimeout(&CIEPSRequest{Sync: true})
+	if !ok || dur != 30*time.Second {
+		t.Fatalf("expected the default 30s timeout for a synchronous request, got %v, %v", dur, ok)
+	}
+
+	cfg.DefaultTimeout = 5 * time.Second
+	dur, ok = cfg.RequestTimeout(&CIEPSRequest{Sync: true})
+	if !ok || dur != 5*time.Second {
+		t.Fatalf("expected the configured 5s timeout, got %v, %v", dur, ok)
+	}
+}
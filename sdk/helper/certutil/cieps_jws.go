@@ -0,0 +1,207 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package certutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// maxCIEPSClockSkew bounds how far a signed CIEPS request or response's
+// 'iat' claim may drift from the verifier's clock before it is rejected,
+// limiting the window in which a captured JWS can be replayed.
+const maxCIEPSClockSkew = 5 * time.Minute
+
+// signedCIEPSRequestPayload is the JSON payload signed by SignRequest. It
+// embeds CIEPSRequest so the signed payload carries every field Vault
+// would otherwise send unauthenticated, plus an 'iat' replay-prevention
+// claim; the JWS 'nonce' header carries the other replay-prevention
+// claim.
+type signedCIEPSRequestPayload struct {
+	CIEPSRequest
+	IssuedAt int64 `json:"iat"`
+}
+
+// signedCIEPSResponsePayload is the symmetric counterpart for
+// VerifyResponse.
+type signedCIEPSResponsePayload struct {
+	CIEPSResponse
+	IssuedAt int64 `json:"iat"`
+}
+
+// SignRequest wraps req in a JWS, compact-serialized, signed by signer and
+// identified by kid (the key ID Vault registered for this mount). The
+// payload carries an 'iat' claim and the JWS protected header carries a
+// fresh 'nonce', mirroring how ACME authenticates request payloads so
+// CIEPS can safely run over untrusted networks or shared brokers.
+func (req *CIEPSRequest) SignRequest(signer crypto.Signer, kid string) ([]byte, error) {
+	if signer == nil {
+		return nil, fmt.Errorf("no signer provided for CIEPS request")
+	}
+
+	alg, err := jwsAlgorithmForSigner(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CIEPS request nonce: %w", err)
+	}
+
+	payload, err := json.Marshal(signedCIEPSRequestPayload{
+		CIEPSRequest: *req,
+		IssuedAt:     time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal CIEPS request payload: %w", err)
+	}
+
+	opts := (&jose.SignerOptions{}).WithHeader("kid", kid).WithHeader("nonce", nonce)
+	joseSigner, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: signer}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct CIEPS request signer: %w", err)
+	}
+
+	jws, err := joseSigner.Sign(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign CIEPS request: %w", err)
+	}
+
+	serialized, err := jws.CompactSerialize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize signed CIEPS request: %w", err)
+	}
+
+	return []byte(serialized), nil
+}
+
+// ParseSignedResponse extracts a CIEPSResponse from a compact-serialized
+// JWS without verifying its signature, retaining the raw token so
+// VerifyResponse can later verify it against the mount's configured
+// JWKS. Callers must call VerifyResponse before trusting the result.
+func ParseSignedResponse(token []byte) (*CIEPSResponse, error) {
+	parts := strings.Split(string(token), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed CIEPS response JWS: expected 3 segments, got %d", len(parts))
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode CIEPS response JWS payload: %w", err)
+	}
+
+	var resp CIEPSResponse
+	if err := json.Unmarshal(payload, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse CIEPS response JWS payload: %w", err)
+	}
+	resp.Signature = string(token)
+
+	return &resp, nil
+}
+
+// VerifyResponse verifies c's JWS signature (populated by
+// ParseSignedResponse) against jwks, the JWK set configured on the mount
+// for the key the external CIEPS service is expected to sign with.
+// expectedUUID must be the request_uuid of the outer envelope this
+// response is a reply to, as tracked independently by the caller (e.g.
+// the UUID of the CIEPSRequest Vault originally sent); it is compared
+// against the verified payload's request_uuid, so a response signed for
+// a different request cannot be substituted in.
+//
+// checkNonce, if non-nil, is called with the JWS protected header's
+// 'nonce' claim so the caller can reject a nonce it has already seen;
+// together with the 'iat' claim (bounded to maxCIEPSClockSkew) this
+// prevents replay of a previously valid, signed response. checkNonce may
+// be nil only when the caller enforces replay prevention some other way
+// (e.g. VerifyCallback's one-time CallbackNonce).
+func (c *CIEPSResponse) VerifyResponse(jwks jose.JSONWebKeySet, expectedUUID string, checkNonce func(nonce string) error) error {
+	if c.Signature == "" {
+		return fmt.Errorf("CIEPS response has no JWS signature to verify; use ParseSignedResponse")
+	}
+
+	if len(jwks.Keys) == 0 {
+		return fmt.Errorf("no verification keys configured for CIEPS response verification")
+	}
+
+	jws, err := jose.ParseSigned(c.Signature)
+	if err != nil {
+		return fmt.Errorf("failed to parse CIEPS response JWS: %w", err)
+	}
+
+	var payload []byte
+	var verifyErr error
+	for _, key := range jwks.Keys {
+		payload, verifyErr = jws.Verify(key.Key)
+		if verifyErr == nil {
+			break
+		}
+	}
+	if verifyErr != nil {
+		return fmt.Errorf("failed to verify CIEPS response signature against configured JWKS: %w", verifyErr)
+	}
+
+	nonce := jws.Signatures[0].Header.Nonce
+	if nonce == "" {
+		return fmt.Errorf("signed CIEPS response is missing its replay-prevention 'nonce' header")
+	}
+	if checkNonce != nil {
+		if err := checkNonce(nonce); err != nil {
+			return fmt.Errorf("CIEPS response nonce rejected: %w", err)
+		}
+	}
+
+	var verified signedCIEPSResponsePayload
+	if err := json.Unmarshal(payload, &verified); err != nil {
+		return fmt.Errorf("failed to parse verified CIEPS response payload: %w", err)
+	}
+
+	if verified.UUID != expectedUUID {
+		return fmt.Errorf("signed CIEPS response request_uuid %q does not match expected request_uuid %q", verified.UUID, expectedUUID)
+	}
+
+	if verified.IssuedAt == 0 {
+		return fmt.Errorf("signed CIEPS response is missing its 'iat' claim")
+	}
+	if skew := time.Since(time.Unix(verified.IssuedAt, 0)); skew > maxCIEPSClockSkew || skew < -maxCIEPSClockSkew {
+		return fmt.Errorf("signed CIEPS response 'iat' claim is outside the allowed %s clock skew window", maxCIEPSClockSkew)
+	}
+
+	return nil
+}
+
+// jwsAlgorithmForSigner picks the JWS signature algorithm matching
+// signer's public key type, mirroring the set of key types Vault already
+// supports for issuer keys.
+func jwsAlgorithmForSigner(signer crypto.Signer) (jose.SignatureAlgorithm, error) {
+	switch pub := signer.Public().(type) {
+	case *rsa.PublicKey:
+		return jose.RS256, nil
+	case *ecdsa.PublicKey:
+		switch pub.Curve.Params().BitSize {
+		case 256:
+			return jose.ES256, nil
+		case 384:
+			return jose.ES384, nil
+		case 521:
+			return jose.ES512, nil
+		default:
+			return "", fmt.Errorf("unsupported ECDSA curve for CIEPS JWS signing: %s", pub.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+		return jose.EdDSA, nil
+	default:
+		return "", fmt.Errorf("unsupported key type for CIEPS JWS signing: %T", pub)
+	}
+}
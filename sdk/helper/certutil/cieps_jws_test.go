@@ -0,0 +1,235 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package certutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+func testGenerateJWSSigner(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate signer key: %v", err)
+	}
+	return key
+}
+
+func testSignCIEPSResponse(t *testing.T, resp CIEPSResponse, iat int64, nonce string, signer crypto.Signer, kid string) []byte {
+	t.Helper()
+
+	alg, err := jwsAlgorithmForSigner(signer)
+	if err != nil {
+		t.Fatalf("failed to pick JWS algorithm: %v", err)
+	}
+
+	payload, err := json.Marshal(signedCIEPSResponsePayload{CIEPSResponse: resp, IssuedAt: iat})
+	if err != nil {
+		t.Fatalf("failed to marshal response payload: %v", err)
+	}
+
+	opts := (&jose.SignerOptions{}).WithHeader("kid", kid)
+	if nonce != "" {
+		opts = opts.WithHeader("nonce", nonce)
+	}
+
+	joseSigner, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: signer}, opts)
+	if err != nil {
+		t.Fatalf("failed to construct signer: %v", err)
+	}
+
+	jws, err := joseSigner.Sign(payload)
+	if err != nil {
+		t.Fatalf("failed to sign response: %v", err)
+	}
+
+	serialized, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatalf("failed to serialize response: %v", err)
+	}
+
+	return []byte(serialized)
+}
+
+func testJWKS(signer crypto.Signer, kid string) jose.JSONWebKeySet {
+	return jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: signer.Public(), KeyID: kid, Algorithm: "ES256", Use: "sig"},
+	}}
+}
+
+func TestCIEPSRequest_SignRequest(t *testing.T) {
+	signer := testGenerateJWSSigner(t)
+	req := &CIEPSRequest{Version: 1, UUID: "req-1", Sync: true}
+
+	t.Run("valid", func(t *testing.T) {
+		token, err := req.SignRequest(signer, "kid-1")
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		jws, err := jose.ParseSigned(string(token))
+		if err != nil {
+			t.Fatalf("failed to parse signed request: %v", err)
+		}
+
+		payload, err := jws.Verify(signer.Public())
+		if err != nil {
+			t.Fatalf("failed to verify signed request: %v", err)
+		}
+
+		var signed signedCIEPSRequestPayload
+		if err := json.Unmarshal(payload, &signed); err != nil {
+			t.Fatalf("failed to unmarshal signed payload: %v", err)
+		}
+		if signed.UUID != "req-1" {
+			t.Fatalf("expected request_uuid to round-trip, got %q", signed.UUID)
+		}
+		if signed.IssuedAt == 0 {
+			t.Fatalf("expected a non-zero 'iat' claim")
+		}
+		if jws.Signatures[0].Header.Nonce == "" {
+			t.Fatalf("expected a non-empty 'nonce' header")
+		}
+		if jws.Signatures[0].Header.KeyID != "kid-1" {
+			t.Fatalf("expected kid to round-trip, got %q", jws.Signatures[0].Header.KeyID)
+		}
+	})
+
+	t.Run("nil signer is rejected", func(t *testing.T) {
+		if _, err := req.SignRequest(nil, "kid-1"); err == nil {
+			t.Fatalf("expected an error for a nil signer")
+		}
+	})
+}
+
+func TestJwsAlgorithmForSigner_UnsupportedKeyType(t *testing.T) {
+	if _, err := jwsAlgorithmForSigner(fakeSigner{}); err == nil {
+		t.Fatalf("expected an error for an unsupported key type")
+	}
+}
+
+// fakeSigner implements crypto.Signer with a public key type not handled
+// by jwsAlgorithmForSigner.
+type fakeSigner struct{}
+
+func (fakeSigner) Public() crypto.PublicKey { return "not-a-real-key" }
+func (fakeSigner) Sign(_ io.Reader, _ []byte, _ crypto.SignerOpts) ([]byte, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func TestParseSignedResponse(t *testing.T) {
+	signer := testGenerateJWSSigner(t)
+	resp := CIEPSResponse{UUID: "resp-1", Certificate: "cert-pem"}
+
+	t.Run("valid", func(t *testing.T) {
+		token := testSignCIEPSResponse(t, resp, time.Now().Unix(), "nonce-1", signer, "kid-1")
+
+		parsed, err := ParseSignedResponse(token)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if parsed.UUID != "resp-1" {
+			t.Fatalf("expected request_uuid to round-trip, got %q", parsed.UUID)
+		}
+		if parsed.Signature != string(token) {
+			t.Fatalf("expected Signature to hold the raw token")
+		}
+	})
+
+	t.Run("malformed token", func(t *testing.T) {
+		if _, err := ParseSignedResponse([]byte("not-a-jws")); err == nil {
+			t.Fatalf("expected an error for a malformed JWS")
+		}
+	})
+}
+
+func TestCIEPSResponse_VerifyResponse(t *testing.T) {
+	signer := testGenerateJWSSigner(t)
+	otherSigner := testGenerateJWSSigner(t)
+	resp := CIEPSResponse{UUID: "resp-1", Certificate: "cert-pem"}
+
+	sign := func(iat int64, nonce string) *CIEPSResponse {
+		token := testSignCIEPSResponse(t, resp, iat, nonce, signer, "kid-1")
+		parsed, err := ParseSignedResponse(token)
+		if err != nil {
+			t.Fatalf("failed to parse signed response: %v", err)
+		}
+		return parsed
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		parsed := sign(time.Now().Unix(), "nonce-1")
+		var seenNonce string
+		checkNonce := func(nonce string) error { seenNonce = nonce; return nil }
+
+		if err := parsed.VerifyResponse(testJWKS(signer, "kid-1"), "resp-1", checkNonce); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if seenNonce != "nonce-1" {
+			t.Fatalf("expected checkNonce to observe the JWS nonce, got %q", seenNonce)
+		}
+	})
+
+	t.Run("empty JWKS is rejected up front", func(t *testing.T) {
+		parsed := sign(time.Now().Unix(), "nonce-1")
+		err := parsed.VerifyResponse(jose.JSONWebKeySet{}, "resp-1", nil)
+		if err == nil {
+			t.Fatalf("expected an error for an empty JWKS")
+		}
+	})
+
+	t.Run("signature from an untrusted key is rejected", func(t *testing.T) {
+		parsed := sign(time.Now().Unix(), "nonce-1")
+		if err := parsed.VerifyResponse(testJWKS(otherSigner, "kid-1"), "resp-1", nil); err == nil {
+			t.Fatalf("expected an error when no configured key matches the signature")
+		}
+	})
+
+	t.Run("mismatched expected UUID is rejected", func(t *testing.T) {
+		parsed := sign(time.Now().Unix(), "nonce-1")
+		if err := parsed.VerifyResponse(testJWKS(signer, "kid-1"), "some-other-request", nil); err == nil {
+			t.Fatalf("expected an error when expectedUUID does not match the signed payload")
+		}
+	})
+
+	t.Run("stale iat is rejected", func(t *testing.T) {
+		parsed := sign(time.Now().Add(-time.Hour).Unix(), "nonce-1")
+		if err := parsed.VerifyResponse(testJWKS(signer, "kid-1"), "resp-1", nil); err == nil {
+			t.Fatalf("expected an error for an 'iat' outside the allowed clock skew")
+		}
+	})
+
+	t.Run("missing nonce header is rejected", func(t *testing.T) {
+		parsed := sign(time.Now().Unix(), "")
+		if err := parsed.VerifyResponse(testJWKS(signer, "kid-1"), "resp-1", nil); err == nil {
+			t.Fatalf("expected an error when the JWS has no replay-prevention nonce")
+		}
+	})
+
+	t.Run("rejected nonce is propagated", func(t *testing.T) {
+		parsed := sign(time.Now().Unix(), "nonce-1")
+		checkNonce := func(nonce string) error { return fmt.Errorf("already seen") }
+		if err := parsed.VerifyResponse(testJWKS(signer, "kid-1"), "resp-1", checkNonce); err == nil {
+			t.Fatalf("expected checkNonce's error to be propagated")
+		}
+	})
+
+	t.Run("missing signature is rejected", func(t *testing.T) {
+		unsigned := &CIEPSResponse{UUID: "resp-1"}
+		if err := unsigned.VerifyResponse(testJWKS(signer, "kid-1"), "resp-1", nil); err == nil {
+			t.Fatalf("expected an error when there is no JWS signature to verify")
+		}
+	})
+}
@@ -0,0 +1,208 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package certutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// testGenerateTestCA generates a throwaway self-signed CA certificate and
+// key, usable both as an issuer and as an OCSP responder.
+func testGenerateTestCA(t *testing.T, cn string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate CA key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse CA certificate: %v", err)
+	}
+
+	return cert, key
+}
+
+// testGenerateTestLeaf issues a leaf certificate signed by ca/caKey.
+func testGenerateTestLeaf(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, serial int64, cn string) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate leaf key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create leaf certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse leaf certificate: %v", err)
+	}
+
+	return cert
+}
+
+func testCreateOCSPResponse(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, serial *big.Int, thisUpdate, nextUpdate time.Time) []byte {
+	t.Helper()
+
+	der, err := ocsp.CreateResponse(ca, ca, ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: serial,
+		ThisUpdate:   thisUpdate,
+		NextUpdate:   nextUpdate,
+	}, caKey)
+	if err != nil {
+		t.Fatalf("failed to create OCSP response: %v", err)
+	}
+
+	return der
+}
+
+func TestCIEPSResponse_ParseOCSPResponse(t *testing.T) {
+	ca, caKey := testGenerateTestCA(t, "Test Root CA")
+	leaf := testGenerateTestLeaf(t, ca, caKey, 42, "leaf.example.com")
+
+	thisUpdate := time.Now().Add(-time.Hour).Truncate(time.Second)
+	nextUpdate := time.Now().Add(time.Hour).Truncate(time.Second)
+	der := testCreateOCSPResponse(t, ca, caKey, leaf.SerialNumber, thisUpdate, nextUpdate)
+
+	newResponse := func() *CIEPSResponse {
+		return &CIEPSResponse{
+			IssuerRef:         "issuer-1",
+			ParsedCertificate: leaf,
+			OCSPResponse:      base64.StdEncoding.EncodeToString(der),
+			OCSPNextUpdate:    nextUpdate.Format(time.RFC3339),
+		}
+	}
+
+	t.Run("valid response", func(t *testing.T) {
+		c := newResponse()
+		if err := c.ParseOCSPResponse(ca, "issuer-1"); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if c.ParsedOCSPResponse == nil {
+			t.Fatalf("expected ParsedOCSPResponse to be populated")
+		}
+	})
+
+	t.Run("no OCSP response is a no-op", func(t *testing.T) {
+		c := &CIEPSResponse{ParsedCertificate: leaf}
+		if err := c.ParseOCSPResponse(nil, ""); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if c.ParsedOCSPResponse != nil {
+			t.Fatalf("expected ParsedOCSPResponse to remain unset")
+		}
+	})
+
+	t.Run("nil issuer is rejected", func(t *testing.T) {
+		c := newResponse()
+		if err := c.ParseOCSPResponse(nil, "issuer-1"); err == nil {
+			t.Fatalf("expected an error when no issuer certificate is supplied")
+		}
+	})
+
+	t.Run("mismatched issuer_ref is rejected", func(t *testing.T) {
+		c := newResponse()
+		if err := c.ParseOCSPResponse(ca, "some-other-issuer"); err == nil {
+			t.Fatalf("expected an error when issuerRef does not match IssuerRef")
+		}
+	})
+
+	t.Run("serial number mismatch is rejected", func(t *testing.T) {
+		other := testGenerateTestLeaf(t, ca, caKey, 99, "other.example.com")
+		otherDER := testCreateOCSPResponse(t, ca, caKey, other.SerialNumber, thisUpdate, nextUpdate)
+
+		c := newResponse()
+		c.OCSPResponse = base64.StdEncoding.EncodeToString(otherDER)
+		if err := c.ParseOCSPResponse(ca, "issuer-1"); err == nil {
+			t.Fatalf("expected an error on serial number mismatch")
+		}
+	})
+
+	t.Run("wrong signer is rejected", func(t *testing.T) {
+		otherCA, otherCAKey := testGenerateTestCA(t, "Test Root CA")
+		badDER := testCreateOCSPResponse(t, otherCA, otherCAKey, leaf.SerialNumber, thisUpdate, nextUpdate)
+
+		c := newResponse()
+		c.OCSPResponse = base64.StdEncoding.EncodeToString(badDER)
+		if err := c.ParseOCSPResponse(ca, "issuer-1"); err == nil {
+			t.Fatalf("expected an error when the OCSP response was signed by a different issuer")
+		}
+	})
+
+	t.Run("mismatched next update claim is rejected", func(t *testing.T) {
+		c := newResponse()
+		c.OCSPNextUpdate = nextUpdate.Add(time.Hour).Format(time.RFC3339)
+		if err := c.ParseOCSPResponse(ca, "issuer-1"); err == nil {
+			t.Fatalf("expected an error when ocsp_next_update does not match the embedded response")
+		}
+	})
+
+	t.Run("malformed base64 is rejected", func(t *testing.T) {
+		c := newResponse()
+		c.OCSPResponse = "not-valid-base64!!"
+		if err := c.ParseOCSPResponse(ca, "issuer-1"); err == nil {
+			t.Fatalf("expected an error on malformed base64")
+		}
+	})
+}
+
+func TestCIEPSResponse_MarshalOCSPResponse(t *testing.T) {
+	c := &CIEPSResponse{}
+
+	if err := c.MarshalOCSPResponse(nil); err == nil {
+		t.Fatalf("expected an error for an empty DER response")
+	}
+
+	der := []byte{0x01, 0x02, 0x03}
+	if err := c.MarshalOCSPResponse(der); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(c.OCSPResponse)
+	if err != nil {
+		t.Fatalf("expected valid base64, got error: %v", err)
+	}
+	if string(decoded) != string(der) {
+		t.Fatalf("expected round-tripped DER bytes to match")
+	}
+}